@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/AsynqLab/asynq"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// bulkBatchSize is how many rows from a bulk upload are enqueued per chunk,
+// so a single oversized upload doesn't hold one goroutine busy for the
+// whole request.
+const bulkBatchSize = 100
+
+// bulkWorkers bounds how many chunks are enqueued to Redis concurrently.
+const bulkWorkers = 4
+
+// bulkBatchIDKey is the task payload field every bulk-enqueued task is
+// tagged with, so GET /tasks/bulk/:batch_id can find them again regardless
+// of which per-host queue they ended up on.
+const bulkBatchIDKey = "_batch_id"
+
+// bulkRecipient is one row of a POST /tasks/bulk upload.
+type bulkRecipient struct {
+	Email    string     `json:"email"`
+	SendAt   *time.Time `json:"send_at,omitempty"`
+	MaxRetry *int       `json:"max_retry,omitempty"`
+}
+
+// bulkRowError reports why a single recipient in a bulk upload could not be
+// enqueued.
+type bulkRowError struct {
+	Index int    `json:"index"`
+	Email string `json:"email"`
+	Error string `json:"error"`
+}
+
+// registerBulkRoutes wires the bulk enqueue endpoint and its batch progress
+// lookup.
+func registerBulkRoutes(app *fiber.App, inspector *asynq.Inspector) {
+	app.Post("/tasks/bulk", func(c *fiber.Ctx) error {
+		var recipients []bulkRecipient
+		if err := json.Unmarshal(c.Body(), &recipients); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Body must be a JSON array of {email, send_at?, max_retry?}",
+			})
+		}
+		if len(recipients) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "At least one recipient is required",
+			})
+		}
+
+		batchID := uuid.NewString()
+		taskIDs, rowErrors := enqueueBulk(batchID, recipients)
+
+		return c.JSON(fiber.Map{
+			"batch_id":   batchID,
+			"enqueued":   taskIDs,
+			"row_errors": rowErrors,
+		})
+	})
+
+	app.Get("/tasks/bulk/:batch_id", func(c *fiber.Ctx) error {
+		batchID := c.Params("batch_id")
+
+		counts, err := batchTaskCounts(inspector, batchID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to retrieve batch progress",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"batch_id":  batchID,
+			"pending":   counts["pending"] + counts["scheduled"],
+			"active":    counts["active"],
+			"completed": counts["completed"],
+			"failed":    counts["archived"] + counts["retry"],
+		})
+	})
+}
+
+// enqueueBulk splits recipients into bulkBatchSize chunks and enqueues each
+// chunk concurrently (bounded by bulkWorkers), tagging every task's payload
+// with batchID so GET /tasks/bulk/:batch_id can add its progress up later.
+func enqueueBulk(batchID string, recipients []bulkRecipient) ([]string, []bulkRowError) {
+	type chunk struct {
+		offset int
+		rows   []bulkRecipient
+	}
+
+	var chunks []chunk
+	for offset := 0; offset < len(recipients); offset += bulkBatchSize {
+		end := offset + bulkBatchSize
+		if end > len(recipients) {
+			end = len(recipients)
+		}
+		chunks = append(chunks, chunk{offset: offset, rows: recipients[offset:end]})
+	}
+
+	var (
+		mu        sync.Mutex
+		taskIDs   []string
+		rowErrors []bulkRowError
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, bulkWorkers)
+	)
+
+	for _, ch := range chunks {
+		ch := ch
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ids, errs := enqueueBulkChunk(batchID, ch.offset, ch.rows)
+
+			mu.Lock()
+			taskIDs = append(taskIDs, ids...)
+			rowErrors = append(rowErrors, errs...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return taskIDs, rowErrors
+}
+
+// enqueueBulkChunk enqueues one chunk of recipients, starting at offset in
+// the original upload (used only to report row_errors with the right
+// index). Each task is routed the same way the "/" handler routes a single
+// send, through deliveryRouter's per-host queues.
+func enqueueBulkChunk(batchID string, offset int, rows []bulkRecipient) ([]string, []bulkRowError) {
+	var (
+		ids  []string
+		errs []bulkRowError
+	)
+
+	for i, row := range rows {
+		if row.Email == "" {
+			errs = append(errs, bulkRowError{Index: offset + i, Email: row.Email, Error: "email is required"})
+			continue
+		}
+
+		payload := map[string]interface{}{"email": row.Email, bulkBatchIDKey: batchID}
+		injectTrace(context.Background(), payload)
+
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			errs = append(errs, bulkRowError{Index: offset + i, Email: row.Email, Error: err.Error()})
+			continue
+		}
+		task := asynq.NewTask(TypeEmailDelivery, payloadBytes)
+
+		opts := []asynq.Option{asynq.Retention(5 * 24 * time.Hour)}
+		if row.SendAt != nil {
+			opts = append(opts, asynq.ProcessAt(*row.SendAt))
+		}
+		if row.MaxRetry != nil {
+			opts = append(opts, asynq.MaxRetry(*row.MaxRetry))
+		}
+
+		info, err := deliveryRouter.Enqueue(task, hostQueueKey(row.Email), opts...)
+		if err != nil {
+			errs = append(errs, bulkRowError{Index: offset + i, Email: row.Email, Error: err.Error()})
+			continue
+		}
+		ids = append(ids, info.ID)
+	}
+
+	return ids, errs
+}
+
+// batchTaskCounts scans every known queue and lifecycle state for tasks
+// tagged with batchID, returning a count per state. asynq's task groups
+// (asynq.Group/Inspector.Groups) only track tasks still waiting to be
+// combined by a GroupAggregator - which this app doesn't configure - not a
+// batch's progress across its whole lifecycle, so batch membership is
+// tracked with an ordinary payload field instead.
+func batchTaskCounts(inspector *asynq.Inspector, batchID string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, queue := range knownQueues() {
+		for _, state := range taskStates {
+			tasks, err := allTasksInQueueState(inspector, queue, state)
+			if err != nil {
+				return nil, err
+			}
+			for _, ti := range tasks {
+				if taskBatchID(ti) == batchID {
+					counts[state]++
+				}
+			}
+		}
+	}
+	return counts, nil
+}
+
+// taskBatchID resolves the bulkBatchIDKey tag from a task's payload,
+// returning "" if it isn't a bulk-enqueued task.
+func taskBatchID(ti *asynq.TaskInfo) string {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(ti.Payload, &payload); err != nil {
+		return ""
+	}
+	id, _ := payload[bulkBatchIDKey].(string)
+	return id
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/AsynqLab/asynq"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/AsynqLab/examples/simple-fiber-dashboard/delivery"
+)
+
+// registerDeliveryRoutes wires the per-host delivery management endpoints:
+// cancelling a host's queued tasks and reporting per-host delivery stats.
+func registerDeliveryRoutes(app *fiber.App, inspector *asynq.Inspector) {
+	app.Delete("/deliveries", func(c *fiber.Ctx) error {
+		host := c.Query("host")
+		if host == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "host query parameter is required",
+			})
+		}
+
+		deleted, err := cancelDeliveriesForHost(inspector, host)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to cancel deliveries",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"host":    host,
+			"deleted": deleted,
+		})
+	})
+
+	app.Get("/deliveries/stats", func(c *fiber.Ctx) error {
+		return c.JSON(deliveryRouter.Stats())
+	})
+}
+
+// cancelDeliveriesForHost removes every pending, scheduled, and retry task
+// across the delivery queues whose payload recipient resolves to host,
+// returning the IDs of the tasks it deleted.
+func cancelDeliveriesForHost(inspector *asynq.Inspector, host string) ([]string, error) {
+	queues := []string{delivery.QueueForHost(host), otherHostQueue, delivery.DeadHostQueue}
+
+	var deleted []string
+	for _, queue := range queues {
+		tasks, err := tasksInQueue(inspector, queue)
+		if err != nil {
+			return nil, err
+		}
+		for _, ti := range tasks {
+			if taskRecipientHost(ti) != host {
+				continue
+			}
+			if err := inspector.DeleteTask(queue, ti.ID); err != nil {
+				continue
+			}
+			deleted = append(deleted, ti.ID)
+		}
+	}
+	return deleted, nil
+}
+
+// tasksInQueue collects every pending, scheduled, and retry TaskInfo on
+// queue, paging through each state's full result set - a host that's piled
+// up a backlog after tripping the failure threshold is exactly the case
+// where there can be more than one page.
+func tasksInQueue(inspector *asynq.Inspector, queue string) ([]*asynq.TaskInfo, error) {
+	var all []*asynq.TaskInfo
+
+	for _, state := range []string{"pending", "scheduled", "retry"} {
+		tasks, err := allTasksInQueueState(inspector, queue, state)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tasks...)
+	}
+
+	return all, nil
+}
+
+// taskRecipientHost resolves the recipient host encoded in an email
+// delivery task's payload, returning "" if it can't be parsed.
+func taskRecipientHost(ti *asynq.TaskInfo) string {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(ti.Payload, &payload); err != nil {
+		return ""
+	}
+	email, _ := payload["email"].(string)
+	return strings.ToLower(delivery.HostFromEmail(email))
+}
@@ -0,0 +1,169 @@
+// Package delivery routes email delivery tasks to per-recipient-domain
+// Asynq queues and isolates hosts that are failing so a single unreachable
+// mail host can't starve delivery to everyone else, in the spirit of
+// GoToSocial's per-host ActivityPub delivery workers.
+package delivery
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AsynqLab/asynq"
+)
+
+// DeadHostQueue is where tasks for a host that has exceeded its failure
+// threshold are enqueued while its cooldown runs. Once the cooldown elapses,
+// only newly enqueued tasks go back to the host's own queue; tasks already
+// on DeadHostQueue are not moved back and the queue is not resumed.
+const DeadHostQueue = "deliver:deadhost"
+
+// QueueForHost returns the Asynq queue name a task for the given recipient
+// domain should be enqueued on, e.g. "deliver:gmail.com".
+func QueueForHost(host string) string {
+	return fmt.Sprintf("deliver:%s", host)
+}
+
+// HostFromEmail extracts the lowercased domain from an email address,
+// returning "unknown" if none can be found.
+func HostFromEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return "unknown"
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+// HostStats is a snapshot of a single host's delivery health.
+type HostStats struct {
+	Inflight            int           `json:"inflight"`
+	Failed              int           `json:"failed"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	Bad                 bool          `json:"bad"`
+	BackoffRemaining    time.Duration `json:"backoff_remaining"`
+}
+
+type hostState struct {
+	inflight            int
+	failed              int
+	consecutiveFailures int
+	bad                 bool
+	backoffUntil        time.Time
+}
+
+// Router enqueues email delivery tasks onto per-host queues and marks hosts
+// "bad" (routing their tasks to DeadHostQueue) once they exceed
+// FailureThreshold consecutive failures, with exponential backoff between
+// cooldown attempts.
+type Router struct {
+	client *asynq.Client
+
+	// FailureThreshold is the number of consecutive failures that marks a
+	// host as bad.
+	FailureThreshold int
+	// BaseBackoff is the cooldown applied the first time a host goes bad.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the doubling of BaseBackoff on repeated bad streaks.
+	MaxBackoff time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// NewRouter creates a Router that enqueues through client.
+func NewRouter(client *asynq.Client, failureThreshold int, baseBackoff, maxBackoff time.Duration) *Router {
+	return &Router{
+		client:           client,
+		FailureThreshold: failureThreshold,
+		BaseBackoff:      baseBackoff,
+		MaxBackoff:       maxBackoff,
+		hosts:            make(map[string]*hostState),
+	}
+}
+
+func (r *Router) stateFor(host string) *hostState {
+	s, ok := r.hosts[host]
+	if !ok {
+		s = &hostState{}
+		r.hosts[host] = s
+	}
+	return s
+}
+
+// Enqueue submits task for delivery to host, routing it to DeadHostQueue
+// instead if the host is currently bad and its cooldown has not elapsed.
+func (r *Router) Enqueue(task *asynq.Task, host string, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	r.mu.Lock()
+	state := r.stateFor(host)
+	queue := QueueForHost(host)
+	if state.bad {
+		if time.Now().Before(state.backoffUntil) {
+			queue = DeadHostQueue
+		} else {
+			// Cooldown elapsed: give the host another chance. The failure
+			// streak is kept, so another failure before a success marks the
+			// host bad again with a doubled backoff.
+			state.bad = false
+		}
+	}
+	state.inflight++
+	r.mu.Unlock()
+
+	opts = append(opts, asynq.Queue(queue))
+	return r.client.Enqueue(task, opts...)
+}
+
+// RecordResult updates host's failure streak after a task finishes
+// processing. err is the error returned by the task handler, or nil on
+// success.
+func (r *Router) RecordResult(host string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := r.stateFor(host)
+	if state.inflight > 0 {
+		state.inflight--
+	}
+
+	if err == nil {
+		state.consecutiveFailures = 0
+		state.bad = false
+		return
+	}
+
+	state.failed++
+	state.consecutiveFailures++
+	if state.consecutiveFailures >= r.FailureThreshold {
+		streak := state.consecutiveFailures - r.FailureThreshold
+		backoff := r.BaseBackoff << streak // exponential doubling
+		if backoff > r.MaxBackoff || backoff <= 0 {
+			backoff = r.MaxBackoff
+		}
+		state.bad = true
+		state.backoffUntil = time.Now().Add(backoff)
+	}
+}
+
+// Stats returns a point-in-time snapshot of every host the Router has seen.
+func (r *Router) Stats() map[string]HostStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]HostStats, len(r.hosts))
+	now := time.Now()
+	for host, state := range r.hosts {
+		remaining := state.backoffUntil.Sub(now)
+		if remaining < 0 {
+			remaining = 0
+		}
+		out[host] = HostStats{
+			Inflight:            state.inflight,
+			Failed:              state.failed,
+			ConsecutiveFailures: state.consecutiveFailures,
+			Bad:                 state.bad,
+			BackoffRemaining:    remaining,
+		}
+	}
+	return out
+}
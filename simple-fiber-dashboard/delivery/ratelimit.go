@@ -0,0 +1,89 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/AsynqLab/asynq"
+	"golang.org/x/time/rate"
+)
+
+// errRateLimited is returned by RateLimitMiddleware when a host's token
+// bucket is empty; the caller's asynq.RetryDelayFunc can use RateLimited to
+// recognize it and pick a short delay before the next attempt. This still
+// consumes one of the task's asynq.MaxRetry attempts like any other handler
+// error - asynq has no notion of a retry that doesn't count against
+// MaxRetry - so callers enqueuing rate-limited task types should configure
+// a generous MaxRetry to avoid tasks being archived as failed purely
+// because they were waiting out someone else's burst.
+type errRateLimited struct {
+	host string
+}
+
+func (e *errRateLimited) Error() string {
+	return fmt.Sprintf("delivery: rate limit exceeded for host %q", e.host)
+}
+
+// RateLimited reports whether err was returned because a host's token
+// bucket was empty.
+func RateLimited(err error) bool {
+	_, ok := err.(*errRateLimited)
+	return ok
+}
+
+// HostLimiter hands out a per-host token bucket limiter, creating one with
+// the configured rate and burst the first time a host is seen.
+type HostLimiter struct {
+	rps   rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewHostLimiter creates a HostLimiter allowing rps tokens per second per
+// host, with the given burst size.
+func NewHostLimiter(rps float64, burst int) *HostLimiter {
+	return &HostLimiter{
+		rps:      rate.Limit(rps),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+func (h *HostLimiter) limiterFor(host string) *rate.Limiter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l, ok := h.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(h.rps, h.burst)
+		h.limiters[host] = l
+	}
+	return l
+}
+
+// RateLimitMiddleware returns an asynq.MiddlewareFunc that throttles
+// taskType task processing per host, where host is derived from the task
+// payload by hostOf. Other task types pass through untouched - hostOf is
+// only meaningful for taskType's payload shape, and a mux-wide limiter
+// would otherwise throttle unrelated task types under a bucket keyed off a
+// host they don't even have. Tasks that arrive when the bucket is empty
+// fail with errRateLimited, which a RetryDelayFunc can use to reschedule
+// quickly - see errRateLimited's doc comment for why that still spends one
+// of the task's normal retry attempts.
+func RateLimitMiddleware(limiter *HostLimiter, taskType string, hostOf func(*asynq.Task) string) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			if t.Type() != taskType {
+				return next.ProcessTask(ctx, t)
+			}
+			host := hostOf(t)
+			if !limiter.limiterFor(host).Allow() {
+				return &errRateLimited{host: host}
+			}
+			return next.ProcessTask(ctx, t)
+		})
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -14,6 +16,12 @@ import (
 	"github.com/AsynqLab/asynqmon"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"go.opentelemetry.io/otel"
+
+	"github.com/AsynqLab/examples/simple-fiber-dashboard/delivery"
+	"github.com/AsynqLab/examples/simple-fiber-dashboard/scheduler"
+	"github.com/AsynqLab/examples/simple-fiber-dashboard/tracing"
+	"github.com/AsynqLab/examples/simple-fiber-dashboard/webhook"
 )
 
 // Task types
@@ -21,10 +29,49 @@ const (
 	TypeEmailDelivery = "email:deliver"
 )
 
-// createEmailDeliveryTask creates a new task for email delivery.
-func createEmailDeliveryTask(email string) *asynq.Task {
+// serviceName identifies this example to the configured trace exporter and
+// is used as the tracer name throughout.
+const serviceName = "simple-fiber-dashboard"
+
+// commonHosts get their own Asynq queue; any other recipient domain shares
+// the deliver:other queue so Config.Queues doesn't need to grow unbounded.
+var commonHosts = []string{"gmail.com", "outlook.com", "yahoo.com"}
+
+const otherHostQueue = "deliver:other"
+
+// deliveryRouter is used wherever an email delivery task is enqueued (the "/"
+// handler, bulk uploads, scheduled digests) to pick a per-host queue, and by
+// handleEmailDeliveryTask to report back success/failure for a host.
+var deliveryRouter *delivery.Router
+
+// simulatedFailureRate is the fraction of simulated sends that fail,
+// letting deliveryRouter's bad-host tracking actually be exercised instead
+// of sitting dead. It's 0 by default so the happy path matches the original
+// example; set SIMULATED_FAILURE_RATE (e.g. "0.3") to try the backoff and
+// deliver:deadhost routing out.
+var simulatedFailureRate = parseFailureRate(os.Getenv("SIMULATED_FAILURE_RATE"))
+
+func parseFailureRate(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	rate, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return rate
+}
+
+// createEmailDeliveryTask creates a new task for email delivery, injecting
+// the span context from ctx so the worker can continue the caller's trace.
+// Pass webhook.OnCompleteWebhook(url) to have the result POSTed there once
+// the task completes.
+func createEmailDeliveryTask(ctx context.Context, email string, opts ...webhook.Option) *asynq.Task {
 	// Task Payload as JSON
 	payload := map[string]interface{}{"email": email}
+	injectTrace(ctx, payload)
+	webhook.Apply(payload, opts...)
+
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		panic(err)
@@ -32,30 +79,89 @@ func createEmailDeliveryTask(email string) *asynq.Task {
 	return asynq.NewTask(TypeEmailDelivery, payloadBytes)
 }
 
-// handleEmailDeliveryTask handles the email delivery task.
-func handleEmailDeliveryTask(_ context.Context, t *asynq.Task) error {
+// handleEmailDeliveryTask handles the email delivery task. Tracing is
+// handled uniformly by TracingMiddleware, so this only needs to do the work,
+// report the outcome back to the delivery router, and write a result that's
+// retrievable through GET /task/:id and, if the task has a webhook
+// configured, delivered there by webhook.Middleware.
+func handleEmailDeliveryTask(ctx context.Context, t *asynq.Task) error {
 	// Extract payload
 	var payload map[string]interface{}
 	err := json.Unmarshal(t.Payload(), &payload)
 	if err != nil {
 		return err
 	}
-	email := payload["email"]
+	email, _ := payload["email"].(string)
+	host := hostQueueKey(email)
 
 	// Simulate sending email
 	log.Printf("Sending email to %s...", email)
 	time.Sleep(2 * time.Second) // Simulate delay
+
+	if rand.Float64() < simulatedFailureRate {
+		err := fmt.Errorf("simulated delivery failure sending to %s", email)
+		log.Printf("Failed to send email to %s: %v", email, err)
+		deliveryRouter.RecordResult(host, err)
+		return err
+	}
 	log.Printf("Email successfully sent to %s", email)
 
+	deliveryRouter.RecordResult(host, nil)
+
+	result, err := json.Marshal(map[string]interface{}{
+		"email":   email,
+		"sent_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := t.ResultWriter().Write(result); err != nil {
+		return err
+	}
+	webhook.SetResult(ctx, result)
+
 	return nil
 }
 
+// hostQueueKey maps an email address to the host key used for routing and
+// stats: a common host's own name, or "other" for everything else.
+func hostQueueKey(email string) string {
+	host := delivery.HostFromEmail(email)
+	for _, h := range commonHosts {
+		if h == host {
+			return h
+		}
+	}
+	return "other"
+}
+
+// knownQueues lists every queue this app ever enqueues a task onto. It's
+// used to locate a task (or count tasks) when the caller only knows its ID
+// or a tag carried in its payload, not which queue it landed on.
+func knownQueues() []string {
+	queues := []string{"default", otherHostQueue, delivery.DeadHostQueue}
+	for _, h := range commonHosts {
+		queues = append(queues, delivery.QueueForHost(h))
+	}
+	return queues
+}
+
 func main() {
+	ctx := context.Background()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.ConfigFromEnv(serviceName))
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
+	defer shutdownTracing(ctx)
+
 	redisConnection := asynq.RedisClientOpt{Addr: "localhost:6379"}
 
 	client := asynq.NewClient(redisConnection)
 	defer client.Close()
 
+	deliveryRouter = delivery.NewRouter(client, 5, 10*time.Second, 10*time.Minute)
+
 	app := fiber.New()
 
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -64,8 +170,16 @@ func main() {
 			return c.SendString("Email is required")
 		}
 
-		task := createEmailDeliveryTask(email)
-		taskInfo, err := client.Enqueue(task, asynq.ProcessIn(2*time.Second), asynq.Retention(5*24*time.Hour))
+		reqCtx, span := otel.Tracer(serviceName).Start(c.Context(), "http.handle_email_request")
+		defer span.End()
+
+		var taskOpts []webhook.Option
+		if url := c.Query("webhook_url"); url != "" {
+			taskOpts = append(taskOpts, webhook.OnCompleteWebhook(url))
+		}
+
+		task := createEmailDeliveryTask(reqCtx, email, taskOpts...)
+		taskInfo, err := deliveryRouter.Enqueue(task, hostQueueKey(email), asynq.ProcessIn(2*time.Second), asynq.Retention(5*24*time.Hour))
 		if err != nil {
 			log.Fatal("Failed to enqueue task:", err)
 		}
@@ -85,7 +199,7 @@ func main() {
 			})
 		}
 
-		taskInfo, err := getTaskInfo(inspector, taskID)
+		taskInfo, err := getTaskInfo(inspector, taskID, c.Query("queue"))
 		if err != nil {
 			if err == asynq.ErrTaskNotFound {
 				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -97,34 +211,75 @@ func main() {
 			})
 		}
 
-		return c.JSON(fiber.Map{
-			"id":              taskInfo.ID,
-			"type":            taskInfo.Type,
-			"payload":         string(taskInfo.Payload),
-			"queue":           taskInfo.Queue,
-			"max_retry":       taskInfo.MaxRetry,
-			"retention":       taskInfo.Retention.String(),
-			"last_failed_at":  taskInfo.LastFailedAt,
-			"is_orphaned":     taskInfo.IsOrphaned,
-			"next_process_at": taskInfo.NextProcessAt,
-			"deadline":        taskInfo.Deadline,
-			"completed_at":    taskInfo.CompletedAt,
-			"retry_count":     taskInfo.Retried,
-		})
+		return c.JSON(taskInfoJSON(taskInfo))
 	})
 
+	registerDeliveryRoutes(app, inspector)
+	registerBulkRoutes(app, inspector)
+	registerTaskRoutes(app, inspector)
+
+	schedulesFile := os.Getenv("SCHEDULES_FILE")
+	if schedulesFile == "" {
+		schedulesFile = "schedules.yaml"
+	}
+	scheduleProvider, err := scheduler.NewFileProvider(schedulesFile)
+	if err != nil {
+		log.Fatal("Failed to load schedules:", err)
+	}
+	scheduler.RegisterRoutes(app, scheduleProvider)
+
+	taskManager, err := scheduler.NewManager(redisConnection, scheduleProvider, 30*time.Second)
+	if err != nil {
+		log.Fatal("Failed to create periodic task manager:", err)
+	}
+
 	monitoring := asynqmon.New(asynqmon.Options{
 		RootPath:     "/monitoring", // RootPath specifies the root for asynqmon app
 		RedisConnOpt: redisConnection,
 	})
 	app.All(fmt.Sprintf("%s/*", monitoring.RootPath()), adaptor.HTTPHandler(monitoring))
 
-	// Task processing server
-	asynqServer := asynq.NewServer(redisConnection, asynq.Config{Concurrency: 10})
+	// Task processing server. Every common host gets its own weighted queue
+	// plus a shared "other" queue and a paused "deadhost" queue for hosts
+	// that have tripped the failure threshold. "default" is kept in the mix
+	// with a small weight too, since it's the conventional asynq queue name
+	// and we'd rather poll it than silently strand a task enqueued there.
+	queues := map[string]int{"default": 1, otherHostQueue: 1, delivery.DeadHostQueue: 1}
+	for _, h := range commonHosts {
+		queues[delivery.QueueForHost(h)] = 3
+	}
+	asynqServer := asynq.NewServer(redisConnection, asynq.Config{
+		Concurrency: 10,
+		Queues:      queues,
+		RetryDelayFunc: func(n int, err error, t *asynq.Task) time.Duration {
+			if delivery.RateLimited(err) {
+				return 2 * time.Second
+			}
+			return asynq.DefaultRetryDelayFunc(n, err, t)
+		},
+	})
+
+	if err := inspector.PauseQueue(delivery.DeadHostQueue); err != nil {
+		log.Printf("Failed to pause %s queue: %v", delivery.DeadHostQueue, err)
+	}
 
-	// Define task handlers
+	// Define task handlers. hostLimiter throttles delivery per host key
+	// (see hostQueueKey) so one spike doesn't exhaust a single mail
+	// provider's own rate limits; every recipient domain outside commonHosts
+	// shares the one "other" bucket.
+	hostLimiter := delivery.NewHostLimiter(5, 10)
+	webhookSecret := os.Getenv("WEBHOOK_SECRET")
 	mux := asynq.NewServeMux()
+	mux.Use(TracingMiddleware)
+	mux.Use(delivery.RateLimitMiddleware(hostLimiter, TypeEmailDelivery, func(t *asynq.Task) string {
+		var payload map[string]interface{}
+		_ = json.Unmarshal(t.Payload(), &payload)
+		email, _ := payload["email"].(string)
+		return hostQueueKey(email)
+	}))
+	mux.Use(webhook.Middleware(webhookSecret, nil))
 	mux.HandleFunc(TypeEmailDelivery, handleEmailDeliveryTask) // Handle email delivery task
+	mux.HandleFunc(TypeScheduledDigest, handleScheduledDigest) // Fan a schedule's recipient list out to individual deliveries
 
 	app.Hooks().OnListen(func(listenData fiber.ListenData) error {
 		if fiber.IsChild() {
@@ -135,6 +290,11 @@ func main() {
 				panic(err)
 			}
 		}()
+		go func() {
+			if err := taskManager.Run(); err != nil {
+				panic(err)
+			}
+		}()
 		return nil
 	})
 
@@ -149,6 +309,7 @@ func main() {
 	log.Println("Received signal:", sig)
 
 	asynqServer.Shutdown()
+	taskManager.Shutdown()
 }
 
 func initQuitCh() chan os.Signal {
@@ -165,6 +326,22 @@ func initQuitCh() chan os.Signal {
 	return sigCh
 }
 
-func getTaskInfo(inspector *asynq.Inspector, taskID string) (*asynq.TaskInfo, error) {
-	return inspector.GetTaskInfo("default", taskID)
+// getTaskInfo looks up a task by ID. If queue is given, it's used directly;
+// otherwise every queue this app enqueues onto is tried in turn, since a
+// task's queue depends on its recipient's host and isn't known up front.
+func getTaskInfo(inspector *asynq.Inspector, taskID, queue string) (*asynq.TaskInfo, error) {
+	if queue != "" {
+		return inspector.GetTaskInfo(queue, taskID)
+	}
+
+	for _, q := range knownQueues() {
+		taskInfo, err := inspector.GetTaskInfo(q, taskID)
+		if err == nil {
+			return taskInfo, nil
+		}
+		if err != asynq.ErrTaskNotFound {
+			return nil, err
+		}
+	}
+	return nil, asynq.ErrTaskNotFound
 }
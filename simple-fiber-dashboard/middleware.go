@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/AsynqLab/asynq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/AsynqLab/examples/simple-fiber-dashboard/tracing"
+)
+
+// tracePayloadKey is the task payload field carrying the injected W3C
+// traceparent/tracestate, matching tracing.Carrier's field names.
+const tracePayloadKey = "_trace"
+
+// injectTrace stamps the span context from ctx into payload under
+// tracePayloadKey so handleEmailDeliveryTask's worker can continue the trace
+// started by the Fiber handler.
+func injectTrace(ctx context.Context, payload map[string]interface{}) {
+	payload[tracePayloadKey] = tracing.Inject(ctx)
+}
+
+// extractTrace pulls the span context embedded by injectTrace out of a
+// decoded task payload, returning ctx unchanged if none is present.
+func extractTrace(ctx context.Context, payload map[string]interface{}) context.Context {
+	raw, ok := payload[tracePayloadKey]
+	if !ok {
+		return ctx
+	}
+	// The payload has been round-tripped through JSON by the time a handler
+	// sees it, so re-decode it into the map[string]string shape Carrier needs.
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return ctx
+	}
+	var carrier tracing.Carrier
+	if err := json.Unmarshal(encoded, &carrier); err != nil {
+		return ctx
+	}
+	return tracing.Extract(ctx, carrier)
+}
+
+// TracingMiddleware extracts the caller's span context from every task's
+// payload and starts a child span named "asynq.process.<TaskType>" around
+// the handler, recording the outcome, retry count, and latency as span
+// attributes. This lets a single Fiber request produce an end-to-end trace
+// through the Asynq worker without every handler doing it by hand.
+func TracingMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var payload map[string]interface{}
+		_ = json.Unmarshal(t.Payload(), &payload)
+
+		ctx = extractTrace(ctx, payload)
+		ctx, span := otel.Tracer(serviceName).Start(ctx, "asynq.process."+t.Type())
+		defer span.End()
+
+		span.SetAttributes(
+			attribute.String("asynq.task_type", t.Type()),
+			attribute.String("asynq.task_id", asynq.GetTaskID(ctx)),
+			attribute.String("asynq.queue", asynq.GetQueueName(ctx)),
+			attribute.Int("asynq.retry_count", asynq.GetRetryCount(ctx)),
+			attribute.Int("asynq.max_retry", asynq.GetMaxRetry(ctx)),
+		)
+
+		start := time.Now()
+		err := next.ProcessTask(ctx, t)
+		span.SetAttributes(attribute.Int64("asynq.latency_ms", time.Since(start).Milliseconds()))
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		return err
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/AsynqLab/asynq"
+)
+
+// TypeScheduledDigest is the periodic task that fans a templated recipient
+// list out into individual TypeEmailDelivery tasks. See schedules.yaml for
+// the sample "daily-digest" schedule.
+const TypeScheduledDigest = "email:scheduled_digest"
+
+type scheduledDigestPayload struct {
+	Recipients []string `json:"recipients"`
+}
+
+// handleScheduledDigest enqueues one email delivery task per recipient in
+// the schedule's templated list.
+func handleScheduledDigest(ctx context.Context, t *asynq.Task) error {
+	var payload scheduledDigestPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return err
+	}
+
+	for _, email := range payload.Recipients {
+		task := createEmailDeliveryTask(ctx, email)
+		if _, err := deliveryRouter.Enqueue(task, hostQueueKey(email)); err != nil {
+			return fmt.Errorf("scheduled digest: enqueue %s: %w", email, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RegisterRoutes wires the CRUD endpoints used to manage cron schedules at
+// runtime: GET/POST /schedules, and PUT/DELETE /schedules/:id.
+func RegisterRoutes(app *fiber.App, provider *FileProvider) {
+	app.Get("/schedules", func(c *fiber.Ctx) error {
+		return c.JSON(provider.List())
+	})
+
+	app.Post("/schedules", func(c *fiber.Ctx) error {
+		var spec ScheduleSpec
+		if err := c.BodyParser(&spec); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid schedule body"})
+		}
+		if spec.ID == "" {
+			spec.ID = uuid.NewString()
+		}
+		if err := validateSpec(spec); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if err := provider.Put(spec); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save schedule"})
+		}
+		return c.Status(fiber.StatusCreated).JSON(spec)
+	})
+
+	app.Put("/schedules/:id", func(c *fiber.Ctx) error {
+		id := c.Params("id")
+
+		var spec ScheduleSpec
+		if err := c.BodyParser(&spec); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid schedule body"})
+		}
+		spec.ID = id
+		if err := validateSpec(spec); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if err := provider.Put(spec); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save schedule"})
+		}
+		return c.JSON(spec)
+	})
+
+	app.Delete("/schedules/:id", func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		if _, ok := provider.Get(id); !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Schedule not found"})
+		}
+		if err := provider.Delete(id); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete schedule"})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+}
+
+func validateSpec(spec ScheduleSpec) error {
+	if spec.TaskType == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "task_type is required")
+	}
+	if !ValidCronSpec(spec.Cron) {
+		return fiber.NewError(fiber.StatusBadRequest, "cron must be a valid 5-field cron expression")
+	}
+	return nil
+}
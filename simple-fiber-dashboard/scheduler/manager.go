@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/AsynqLab/asynq"
+)
+
+// Manager wraps asynq.PeriodicTaskManager, periodically re-syncing cron
+// entries from a PeriodicTaskConfigProvider so schedule edits take effect
+// without restarting the process.
+type Manager struct {
+	ptm *asynq.PeriodicTaskManager
+}
+
+// NewManager builds a Manager that polls provider every syncInterval.
+func NewManager(redisConnOpt asynq.RedisConnOpt, provider asynq.PeriodicTaskConfigProvider, syncInterval time.Duration) (*Manager, error) {
+	ptm, err := asynq.NewPeriodicTaskManager(asynq.PeriodicTaskManagerOpts{
+		RedisConnOpt:               redisConnOpt,
+		PeriodicTaskConfigProvider: provider,
+		SyncInterval:               syncInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{ptm: ptm}, nil
+}
+
+// Run starts the manager's scheduler and sync loop. It blocks until
+// Shutdown is called, matching asynq.Server.Run's contract.
+func (m *Manager) Run() error {
+	return m.ptm.Run()
+}
+
+// Shutdown stops the scheduler and sync loop.
+func (m *Manager) Shutdown() {
+	m.ptm.Shutdown()
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/AsynqLab/asynq"
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultListState is used by GET /tasks when no state query param is given.
+const defaultListState = "pending"
+
+// taskStates enumerates every lifecycle state a task can be listed in.
+var taskStates = []string{"pending", "active", "scheduled", "retry", "archived", "completed"}
+
+// registerTaskRoutes wires the operator-facing task inspection and control
+// endpoints backed by asynq.Inspector: listing with filters/pagination, and
+// retry/archive/delete/pause/resume actions equivalent to what asynqmon
+// offers through its UI, but scriptable.
+func registerTaskRoutes(app *fiber.App, inspector *asynq.Inspector) {
+	app.Get("/tasks", func(c *fiber.Ctx) error {
+		queues := queuesToQuery(c.Query("queue", ""))
+		taskType := c.Query("type", "")
+		page := c.QueryInt("page", 1)
+		size := c.QueryInt("size", 20)
+		if page < 1 {
+			page = 1
+		}
+		if size < 1 {
+			size = 20
+		}
+
+		states := statesToQuery(c.Query("state", ""), c.QueryBool("only_failed", false))
+
+		var tasks []*asynq.TaskInfo
+		for _, queue := range queues {
+			for _, state := range states {
+				ts, err := listTasksByState(inspector, queue, state, page, size)
+				if err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+				}
+				tasks = append(tasks, ts...)
+			}
+		}
+
+		if taskType != "" {
+			tasks = filterByType(tasks, taskType)
+		}
+
+		data := make([]fiber.Map, 0, len(tasks))
+		for _, ti := range tasks {
+			data = append(data, taskInfoJSON(ti))
+		}
+
+		return c.JSON(fiber.Map{
+			"count": len(data),
+			"data":  data,
+		})
+	})
+
+	app.Post("/tasks/:id/retry", func(c *fiber.Ctx) error {
+		err := withTaskQueue(c, inspector, func(queue string) error {
+			return inspector.RunTask(queue, c.Params("id"))
+		})
+		if err != nil {
+			return taskActionError(c, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	app.Post("/tasks/:id/archive", func(c *fiber.Ctx) error {
+		err := withTaskQueue(c, inspector, func(queue string) error {
+			return inspector.ArchiveTask(queue, c.Params("id"))
+		})
+		if err != nil {
+			return taskActionError(c, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	app.Delete("/tasks/:id", func(c *fiber.Ctx) error {
+		err := withTaskQueue(c, inspector, func(queue string) error {
+			return inspector.DeleteTask(queue, c.Params("id"))
+		})
+		if err != nil {
+			return taskActionError(c, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	app.Post("/queues/:name/pause", func(c *fiber.Ctx) error {
+		if err := inspector.PauseQueue(c.Params("name")); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	app.Post("/queues/:name/resume", func(c *fiber.Ctx) error {
+		if err := inspector.UnpauseQueue(c.Params("name")); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+}
+
+// queuesToQuery resolves the queue query param into the set of queues to
+// list: just that one if given, otherwise every queue this app enqueues
+// onto, since a task's queue depends on its recipient's host and a caller
+// scripting against these endpoints shouldn't have to know that mapping.
+func queuesToQuery(queue string) []string {
+	if queue != "" {
+		return []string{queue}
+	}
+	return knownQueues()
+}
+
+// withTaskQueue calls action with the queue named by the request's queue
+// query param, or, if it's absent, with each of knownQueues() in turn until
+// one succeeds - the same scan getTaskInfo uses, since a task ID alone
+// doesn't say which queue it landed on.
+func withTaskQueue(c *fiber.Ctx, inspector *asynq.Inspector, action func(queue string) error) error {
+	if queue := c.Query("queue"); queue != "" {
+		return action(queue)
+	}
+
+	for _, queue := range knownQueues() {
+		err := action(queue)
+		if err == nil {
+			return nil
+		}
+		if err != asynq.ErrTaskNotFound {
+			return err
+		}
+	}
+	return asynq.ErrTaskNotFound
+}
+
+// statesToQuery resolves the state/only_failed query params into the set of
+// task states to list. only_failed is shorthand for the states a task
+// passes through once it has failed at least once.
+func statesToQuery(state string, onlyFailed bool) []string {
+	if state != "" {
+		return []string{state}
+	}
+	if onlyFailed {
+		return []string{"retry", "archived"}
+	}
+	return []string{defaultListState}
+}
+
+func listTasksByState(inspector *asynq.Inspector, queue, state string, page, size int) ([]*asynq.TaskInfo, error) {
+	opts := []asynq.ListOption{asynq.Page(page), asynq.PageSize(size)}
+	switch state {
+	case "pending":
+		return inspector.ListPendingTasks(queue, opts...)
+	case "active":
+		return inspector.ListActiveTasks(queue, opts...)
+	case "scheduled":
+		return inspector.ListScheduledTasks(queue, opts...)
+	case "retry":
+		return inspector.ListRetryTasks(queue, opts...)
+	case "archived":
+		return inspector.ListArchivedTasks(queue, opts...)
+	case "completed":
+		return inspector.ListCompletedTasks(queue, opts...)
+	default:
+		return nil, fmt.Errorf("unknown state %q", state)
+	}
+}
+
+// allTasksInQueueState collects every TaskInfo in queue/state, paging
+// through the full result set rather than just the first page.
+func allTasksInQueueState(inspector *asynq.Inspector, queue, state string) ([]*asynq.TaskInfo, error) {
+	const pageSize = 100
+
+	var all []*asynq.TaskInfo
+	for page := 1; ; page++ {
+		tasks, err := listTasksByState(inspector, queue, state, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tasks...)
+		if len(tasks) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+func filterByType(tasks []*asynq.TaskInfo, taskType string) []*asynq.TaskInfo {
+	filtered := tasks[:0]
+	for _, ti := range tasks {
+		if ti.Type == taskType {
+			filtered = append(filtered, ti)
+		}
+	}
+	return filtered
+}
+
+func taskActionError(c *fiber.Ctx, err error) error {
+	if err == asynq.ErrTaskNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
+// taskInfoJSON renders a TaskInfo the same way for GET /task/:id and
+// GET /tasks, including the result bytes written via t.ResultWriter().
+func taskInfoJSON(taskInfo *asynq.TaskInfo) fiber.Map {
+	return fiber.Map{
+		"id":              taskInfo.ID,
+		"type":            taskInfo.Type,
+		"queue":           taskInfo.Queue,
+		"payload":         string(taskInfo.Payload),
+		"result":          string(taskInfo.Result),
+		"max_retry":       taskInfo.MaxRetry,
+		"retention":       taskInfo.Retention.String(),
+		"last_failed_at":  taskInfo.LastFailedAt,
+		"is_orphaned":     taskInfo.IsOrphaned,
+		"next_process_at": taskInfo.NextProcessAt,
+		"deadline":        taskInfo.Deadline,
+		"completed_at":    taskInfo.CompletedAt,
+		"retry_count":     taskInfo.Retried,
+	}
+}
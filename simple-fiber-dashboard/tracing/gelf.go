@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// gelfExporter is a minimal sdktrace.SpanExporter that writes one gzip-compressed
+// GELF message per span to a Graylog UDP input. It does not implement GELF's
+// chunking scheme, so it assumes individual span messages fit in one datagram.
+type gelfExporter struct {
+	conn *net.UDPConn
+	host string
+}
+
+func newGELFExporter(addr string) (*gelfExporter, error) {
+	if addr == "" {
+		addr = "localhost:12201"
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "asynq-example"
+	}
+	return &gelfExporter{conn: conn, host: hostname}, nil
+}
+
+type gelfMessage struct {
+	Version      string  `json:"version"`
+	Host         string  `json:"host"`
+	ShortMessage string  `json:"short_message"`
+	Timestamp    float64 `json:"timestamp"`
+	Level        int     `json:"level"`
+	TraceID      string  `json:"_trace_id"`
+	SpanID       string  `json:"_span_id"`
+	DurationMs   float64 `json:"_duration_ms"`
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *gelfExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	for _, span := range spans {
+		msg := gelfMessage{
+			Version:      "1.1",
+			Host:         e.host,
+			ShortMessage: span.Name(),
+			Timestamp:    float64(span.StartTime().UnixNano()) / float64(time.Second),
+			Level:        6,
+			TraceID:      span.SpanContext().TraceID().String(),
+			SpanID:       span.SpanContext().SpanID().String(),
+			DurationMs:   float64(span.EndTime().Sub(span.StartTime())) / float64(time.Millisecond),
+		}
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if err := e.send(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *gelfExporter) send(payload []byte) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(payload); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	_, err := e.conn.Write(buf.Bytes())
+	return err
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (e *gelfExporter) Shutdown(context.Context) error {
+	return e.conn.Close()
+}
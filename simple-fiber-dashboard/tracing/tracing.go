@@ -0,0 +1,138 @@
+// Package tracing configures OpenTelemetry tracing and carries W3C trace
+// context across the Asynq queue boundary so a single trace can span the
+// Fiber handler that enqueues a task and the worker that processes it.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// Exporter identifies which span exporter backend Setup should build.
+type Exporter string
+
+const (
+	ExporterOTLPHTTP Exporter = "otlp-http"
+	ExporterStdout   Exporter = "stdout"
+	ExporterGELF     Exporter = "gelf"
+)
+
+// Config controls how the global TracerProvider is constructed.
+type Config struct {
+	ServiceName string
+
+	Exporter Exporter
+
+	// OTLPEndpoint is used when Exporter is ExporterOTLPHTTP, e.g. "localhost:4318".
+	OTLPEndpoint string
+
+	// GELFAddr is the "host:port" of a Graylog UDP input, used when Exporter
+	// is ExporterGELF.
+	GELFAddr string
+}
+
+// ConfigFromEnv builds a Config from TRACE_EXPORTER, OTEL_EXPORTER_OTLP_ENDPOINT
+// and GELF_ADDR, defaulting to the stdout exporter so the example works with
+// no extra setup.
+func ConfigFromEnv(serviceName string) Config {
+	cfg := Config{
+		ServiceName:  serviceName,
+		Exporter:     Exporter(os.Getenv("TRACE_EXPORTER")),
+		OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		GELFAddr:     os.Getenv("GELF_ADDR"),
+	}
+	if cfg.Exporter == "" {
+		cfg.Exporter = ExporterStdout
+	}
+	return cfg
+}
+
+// Setup installs a global TracerProvider built from cfg along with a W3C
+// tracecontext+baggage propagator, and returns a shutdown func that flushes
+// and stops the exporter. Callers should defer shutdown(ctx) in main().
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	exp, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: build resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterOTLPHTTP:
+		endpoint := cfg.OTLPEndpoint
+		if endpoint == "" {
+			endpoint = "localhost:4318"
+		}
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+	case ExporterGELF:
+		return newGELFExporter(cfg.GELFAddr)
+	case ExporterStdout, "":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", cfg.Exporter)
+	}
+}
+
+// Carrier is a map-backed propagation.TextMapCarrier used to move trace
+// context through a JSON task payload.
+type Carrier map[string]string
+
+func (c Carrier) Get(key string) string       { return c[key] }
+func (c Carrier) Set(key, value string)       { c[key] = value }
+func (c Carrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Inject serializes the span context carried by ctx into a Carrier suitable
+// for embedding in a task payload (see the "_trace" field convention used by
+// the email delivery task).
+func Inject(ctx context.Context) Carrier {
+	carrier := Carrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract rebuilds a context carrying the remote span context encoded in
+// carrier, so a worker can start a child span linked to the originating
+// request.
+func Extract(ctx context.Context, carrier Carrier) context.Context {
+	if carrier == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/AsynqLab/asynq"
+)
+
+// deliveryAttempts and deliveryBackoff govern webhook delivery's own
+// retry loop, kept separate from the task's own asynq retry policy so a
+// flaky callback URL never re-runs the underlying task.
+const (
+	deliveryAttempts = 3
+	deliveryBackoff  = time.Second
+)
+
+// Middleware returns an asynq.MiddlewareFunc that, after a handler
+// completes successfully, POSTs its result (see SetResult) to the URL
+// stored by OnCompleteWebhook, signing the body with HMAC-SHA256 over
+// secret in the X-Webhook-Signature header.
+func Middleware(secret string, httpClient *http.Client) asynq.MiddlewareFunc {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			var payload map[string]interface{}
+			_ = json.Unmarshal(t.Payload(), &payload)
+			url, _ := payload[URLPayloadKey].(string)
+
+			ctx, box := withResultBox(ctx)
+			if err := next.ProcessTask(ctx, t); err != nil {
+				return err
+			}
+
+			if url != "" {
+				deliver(httpClient, url, secret, box.data)
+			}
+			return nil
+		})
+	}
+}
+
+// deliver POSTs body to url, retrying a handful of times with a doubling
+// backoff before giving up and logging the failure.
+func deliver(client *http.Client, url, secret string, body []byte) {
+	backoff := deliveryBackoff
+	for attempt := 1; attempt <= deliveryAttempts; attempt++ {
+		err := post(client, url, secret, body)
+		if err == nil {
+			return
+		}
+		if attempt == deliveryAttempts {
+			log.Printf("webhook: giving up delivering to %s after %d attempts: %v", url, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func post(client *http.Client, url, secret string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Signature", sign(secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
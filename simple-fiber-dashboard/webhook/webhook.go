@@ -0,0 +1,54 @@
+// Package webhook lets a task ask to have its result POSTed to a callback
+// URL once it completes successfully, independent of the task's own retry
+// policy.
+package webhook
+
+import "context"
+
+// URLPayloadKey is the task payload field OnCompleteWebhook writes to and
+// Middleware reads from.
+const URLPayloadKey = "_webhook_url"
+
+// Option customizes a task payload envelope before it is enqueued.
+type Option func(map[string]interface{})
+
+// OnCompleteWebhook stores url in the payload envelope so Middleware POSTs
+// the task's result there after the handler completes successfully. It's
+// named and shaped like an asynq.Option (asynq.Retention, asynq.MaxRetry,
+// ...) for familiarity, but asynq has no extension point for a per-task
+// delivery callback, so this works by tagging the payload instead.
+func OnCompleteWebhook(url string) Option {
+	return func(payload map[string]interface{}) {
+		payload[URLPayloadKey] = url
+	}
+}
+
+// Apply runs every opt against payload.
+func Apply(payload map[string]interface{}, opts ...Option) {
+	for _, opt := range opts {
+		opt(payload)
+	}
+}
+
+type resultBoxKey struct{}
+
+type resultBox struct {
+	data []byte
+}
+
+// withResultBox installs an empty box into ctx that the task handler fills
+// via SetResult, so Middleware can see what was written after
+// next.ProcessTask returns.
+func withResultBox(ctx context.Context) (context.Context, *resultBox) {
+	box := &resultBox{}
+	return context.WithValue(ctx, resultBoxKey{}, box), box
+}
+
+// SetResult records the bytes a handler wrote via t.ResultWriter().Write so
+// the enclosing Middleware can deliver them to the task's webhook, if any.
+// It has no effect outside of a Middleware-wrapped handler.
+func SetResult(ctx context.Context, result []byte) {
+	if box, ok := ctx.Value(resultBoxKey{}).(*resultBox); ok {
+		box.data = result
+	}
+}